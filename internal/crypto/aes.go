@@ -1,32 +1,180 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 )
 
+// Mode selects the AES block cipher mode used by EncryptAES256 and
+// DecryptAES256.
+type Mode int
+
+const (
+	// ModeGCM encrypts with AES-256-GCM and authenticates the ciphertext,
+	// producing nonce || ciphertext || tag. This is the default mode and
+	// should be preferred unless CBC is required for interop.
+	ModeGCM Mode = iota
+
+	// ModeCBC encrypts with AES-256-CBC using the caller-supplied IV and
+	// PKCS#7 padding. CBC provides no integrity protection on its own and
+	// is only offered for compatibility with systems that require it. Do
+	// not expose DecryptAES256(..., ModeCBC) to an attacker who can
+	// observe errors or timing without pairing it with your own MAC over
+	// the ciphertext first (verify-then-decrypt) — without that, it is
+	// vulnerable to a padding-oracle attack.
+	ModeCBC
+)
+
+var (
+	// ErrCiphertextTooShort is returned when encrypted data is too small to
+	// contain the nonce, salt or header it is expected to be prefixed with,
+	// or, for CBC, is empty or not a whole number of AES blocks.
+	ErrCiphertextTooShort = errors.New("crypto: ciphertext too short")
+
+	// ErrInvalidPadding is returned when CBC decryption finds PKCS#7 padding
+	// that does not match the expected format.
+	ErrInvalidPadding = errors.New("crypto: invalid padding")
+)
+
+// GenerateIV returns a random initialization vector sized for use with
+// ModeCBC. ModeGCM generates its own nonce internally and does not need one.
 func GenerateIV() ([]byte, error) {
 	iv := make([]byte, aes.BlockSize)
 	_, err := rand.Read(iv)
 	return iv, err
 }
 
-func EncryptAES256(data, key, iv []byte) ([]byte, error) {
+// EncryptAES256 encrypts data with key under the given mode. For ModeGCM, iv
+// is ignored and a fresh random nonce is generated and prepended to the
+// output. For ModeCBC, iv is used verbatim and must be aes.BlockSize bytes,
+// as produced by GenerateIV.
+func EncryptAES256(data, key, iv []byte, mode Mode) ([]byte, error) {
+	switch mode {
+	case ModeCBC:
+		return encryptCBC(data, key, iv)
+	case ModeGCM:
+		return encryptGCM(data, key)
+	default:
+		return nil, fmt.Errorf("crypto: unknown mode %d", mode)
+	}
+}
+
+// DecryptAES256 reverses EncryptAES256. The mode must match the one used to
+// produce encryptedData.
+func DecryptAES256(encryptedData, key, iv []byte, mode Mode) ([]byte, error) {
+	switch mode {
+	case ModeCBC:
+		return decryptCBC(encryptedData, key, iv)
+	case ModeGCM:
+		return decryptGCM(encryptedData, key)
+	default:
+		return nil, fmt.Errorf("crypto: unknown mode %d", mode)
+	}
+}
+
+func encryptGCM(data, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]byte, len(data))
-	block.Encrypt(out, []byte(data))
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func decryptGCM(encryptedData, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(encryptedData) < gcm.NonceSize() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, ciphertext := encryptedData[:gcm.NonceSize()], encryptedData[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func encryptCBC(data, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("crypto: iv must be %d bytes, got %d", aes.BlockSize, len(iv))
+	}
+	padded := pkcs7Pad(data, aes.BlockSize)
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
 	return out, nil
 }
 
-func DecryptAES256(encryptedData, key, iv []byte) ([]byte, error) {
+// decryptCBC decrypts and unpads a CBC ciphertext. CBC alone is not
+// authenticated, so a caller who lets an attacker submit arbitrary
+// ciphertexts here and observe whether decryption succeeds (directly, or via
+// timing) is exposing a padding oracle; see ModeCBC's doc comment.
+func decryptCBC(encryptedData, key, iv []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("crypto: iv must be %d bytes, got %d", aes.BlockSize, len(iv))
+	}
+	if len(encryptedData) == 0 || len(encryptedData)%aes.BlockSize != 0 {
+		return nil, ErrCiphertextTooShort
+	}
 	out := make([]byte, len(encryptedData))
-	block.Decrypt(out, encryptedData)
-	return out, nil
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, encryptedData)
+	return pkcs7Unpad(out, aes.BlockSize)
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padLen := blockSize - len(data)%blockSize
+	padding := bytes.Repeat([]byte{byte(padLen)}, padLen)
+	return append(append([]byte{}, data...), padding...)
+}
+
+// pkcs7Unpad validates and strips PKCS#7 padding in constant time: it never
+// branches on whether an individual padding byte is correct, so a caller
+// timing this function learns nothing about how much of the padding was
+// wrong (see decryptCBC's doc comment on the padding-oracle risk this
+// guards against).
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, ErrInvalidPadding
+	}
+	padLen := int(data[len(data)-1])
+
+	good := subtle.ConstantTimeLessOrEq(1, padLen) & subtle.ConstantTimeLessOrEq(padLen, blockSize)
+
+	// Clamp the slice length so it's always in range; this clamp does not
+	// influence `good`, which was already decided above from the
+	// unclamped padLen.
+	checkLen := padLen
+	if checkLen < 1 || checkLen > blockSize {
+		checkLen = blockSize
+	}
+	for _, b := range data[len(data)-checkLen:] {
+		good &= subtle.ConstantTimeByteEq(b, byte(padLen))
+	}
+
+	if good != 1 {
+		return nil, ErrInvalidPadding
+	}
+	return data[:len(data)-padLen], nil
 }
@@ -0,0 +1,277 @@
+package crypto
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDFID identifies a KDF implementation in the small header MarshalKDFHeader
+// writes alongside a salt, so UnmarshalKDFHeader can reconstruct the same
+// KDF (with the same parameters) to derive a matching key at decrypt time.
+type KDFID byte
+
+const (
+	// KDFIDPBKDF2SHA256 identifies PBKDF2KDF, this package's long-standing
+	// default.
+	KDFIDPBKDF2SHA256 KDFID = iota
+	// KDFIDScrypt identifies ScryptKDF.
+	KDFIDScrypt
+	// KDFIDArgon2id identifies Argon2idKDF.
+	KDFIDArgon2id
+)
+
+// KDF derives key material from a password and salt. Implementations also
+// know how to serialize their own tunable parameters (Marshal) and restore
+// them (Unmarshal), so a chosen KDF and its parameters can travel alongside
+// ciphertext via MarshalKDFHeader/UnmarshalKDFHeader.
+type KDF interface {
+	ID() KDFID
+	Derive(password, salt []byte, keyLen int) ([]byte, error)
+	Marshal() []byte
+	Unmarshal(params []byte) error
+}
+
+func newKDF(id KDFID) (KDF, error) {
+	switch id {
+	case KDFIDPBKDF2SHA256:
+		return &PBKDF2KDF{}, nil
+	case KDFIDScrypt:
+		return &ScryptKDF{}, nil
+	case KDFIDArgon2id:
+		return &Argon2idKDF{}, nil
+	default:
+		return nil, fmt.Errorf("crypto: unknown KDF id %d", id)
+	}
+}
+
+// MarshalKDFHeader encodes k's ID and parameters into a small self-describing
+// header: a one-byte KDFID, a one-byte parameter length, then the parameters
+// themselves.
+func MarshalKDFHeader(k KDF) []byte {
+	params := k.Marshal()
+	header := make([]byte, 0, 2+len(params))
+	header = append(header, byte(k.ID()), byte(len(params)))
+	return append(header, params...)
+}
+
+// UnmarshalKDFHeader reverses MarshalKDFHeader, returning the reconstructed
+// KDF and the number of bytes consumed from data.
+func UnmarshalKDFHeader(data []byte) (KDF, int, error) {
+	if len(data) < 2 {
+		return nil, 0, ErrCiphertextTooShort
+	}
+	k, err := newKDF(KDFID(data[0]))
+	if err != nil {
+		return nil, 0, err
+	}
+	paramLen := int(data[1])
+	if len(data) < 2+paramLen {
+		return nil, 0, ErrCiphertextTooShort
+	}
+	if err := k.Unmarshal(data[2 : 2+paramLen]); err != nil {
+		return nil, 0, err
+	}
+	return k, 2 + paramLen, nil
+}
+
+// PBKDF2KDF derives keys with PBKDF2-HMAC-SHA256, matching the behavior
+// DeriveKey has always had. Iterations defaults to the package-level
+// Iterations constant when zero.
+type PBKDF2KDF struct {
+	Iterations int
+}
+
+func (k *PBKDF2KDF) ID() KDFID { return KDFIDPBKDF2SHA256 }
+
+func (k *PBKDF2KDF) iterations() int {
+	if k.Iterations == 0 {
+		return Iterations
+	}
+	return k.Iterations
+}
+
+// Derive implements KDF.
+func (k *PBKDF2KDF) Derive(password, salt []byte, keyLen int) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, string(password), salt, k.iterations(), keyLen)
+}
+
+// Marshal implements KDF.
+func (k *PBKDF2KDF) Marshal() []byte {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(k.iterations()))
+	return buf
+}
+
+// Unmarshal implements KDF.
+func (k *PBKDF2KDF) Unmarshal(params []byte) error {
+	if len(params) != 4 {
+		return fmt.Errorf("crypto: PBKDF2KDF expects 4 bytes of params, got %d", len(params))
+	}
+	k.Iterations = int(binary.BigEndian.Uint32(params))
+	return nil
+}
+
+// ScryptKDF derives keys with scrypt. N must be a power of two greater than
+// one; R and P are scrypt's block size and parallelization parameters.
+type ScryptKDF struct {
+	N, R, P int
+}
+
+func (k *ScryptKDF) ID() KDFID { return KDFIDScrypt }
+
+// Derive implements KDF.
+func (k *ScryptKDF) Derive(password, salt []byte, keyLen int) ([]byte, error) {
+	return scrypt.Key(password, salt, k.N, k.R, k.P, keyLen)
+}
+
+// Marshal implements KDF.
+func (k *ScryptKDF) Marshal() []byte {
+	buf := make([]byte, 12)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(k.N))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(k.R))
+	binary.BigEndian.PutUint32(buf[8:12], uint32(k.P))
+	return buf
+}
+
+// Unmarshal implements KDF.
+func (k *ScryptKDF) Unmarshal(params []byte) error {
+	if len(params) != 12 {
+		return fmt.Errorf("crypto: ScryptKDF expects 12 bytes of params, got %d", len(params))
+	}
+	k.N = int(binary.BigEndian.Uint32(params[0:4]))
+	k.R = int(binary.BigEndian.Uint32(params[4:8]))
+	k.P = int(binary.BigEndian.Uint32(params[8:12]))
+	return nil
+}
+
+// Argon2idKDF derives keys with Argon2id. Time and Memory are the iteration
+// and memory (KiB) cost parameters; Threads is the degree of parallelism.
+type Argon2idKDF struct {
+	Time, Memory uint32
+	Threads      uint8
+}
+
+func (k *Argon2idKDF) ID() KDFID { return KDFIDArgon2id }
+
+// Derive implements KDF.
+func (k *Argon2idKDF) Derive(password, salt []byte, keyLen int) ([]byte, error) {
+	return argon2.IDKey(password, salt, k.Time, k.Memory, k.Threads, uint32(keyLen)), nil
+}
+
+// Marshal implements KDF.
+func (k *Argon2idKDF) Marshal() []byte {
+	buf := make([]byte, 9)
+	binary.BigEndian.PutUint32(buf[0:4], k.Time)
+	binary.BigEndian.PutUint32(buf[4:8], k.Memory)
+	buf[8] = k.Threads
+	return buf
+}
+
+// Unmarshal implements KDF.
+func (k *Argon2idKDF) Unmarshal(params []byte) error {
+	if len(params) != 9 {
+		return fmt.Errorf("crypto: Argon2idKDF expects 9 bytes of params, got %d", len(params))
+	}
+	k.Time = binary.BigEndian.Uint32(params[0:4])
+	k.Memory = binary.BigEndian.Uint32(params[4:8])
+	k.Threads = params[8]
+	return nil
+}
+
+// calibrationPassword is used only to time trial derivations; it never
+// derives a key that protects real data.
+var calibrationPassword = []byte("crypto-kdf-calibration")
+
+// Calibrate picks a ScryptKDF whose cost is as high as this machine can
+// afford within targetDuration and memoryLimit bytes, mirroring how restic
+// tunes scrypt's N at repository-init time so weaker devices still get the
+// strongest KDF they can afford. It starts at N=2^15, r=8, p=1 and doubles N
+// until a trial derivation would exceed targetDuration or memoryLimit
+// (scrypt's working set is N*r*128 bytes).
+func Calibrate(targetDuration time.Duration, memoryLimit uint64) (KDF, error) {
+	return CalibrateScrypt(targetDuration, memoryLimit)
+}
+
+// CalibrateScrypt is the scrypt-specific implementation behind Calibrate. It
+// returns an error if memoryLimit is too small for even the starting N=2^15
+// floor, rather than silently returning a KDF that exceeds the caller's
+// limit.
+func CalibrateScrypt(targetDuration time.Duration, memoryLimit uint64) (*ScryptKDF, error) {
+	const r, p = 8, 1
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	n := 1 << 15
+	if uint64(n)*uint64(r)*128 > memoryLimit {
+		return nil, fmt.Errorf("crypto: memory limit %d is too small for the minimum scrypt working set of %d bytes", memoryLimit, uint64(n)*uint64(r)*128)
+	}
+	for {
+		start := time.Now()
+		if _, err := scrypt.Key(calibrationPassword, salt, n, r, p, KeySize); err != nil {
+			return nil, err
+		}
+		if time.Since(start) > targetDuration {
+			// n overran the budget; fall back to the last N that was
+			// actually timed within it, unless n is already the floor.
+			if n > 1<<15 {
+				n /= 2
+			}
+			break
+		}
+		if uint64(n*2)*uint64(r)*128 > memoryLimit {
+			break
+		}
+		n *= 2
+	}
+	return &ScryptKDF{N: n, R: r, P: p}, nil
+}
+
+// CalibrateArgon2id is Argon2id's analogue of CalibrateScrypt: it starts
+// from a modest time/memory cost and increases both until a trial
+// derivation would exceed targetDuration or memoryLimit bytes. It returns an
+// error if memoryLimit is too small for even the starting 64 MiB floor.
+func CalibrateArgon2id(targetDuration time.Duration, memoryLimit uint64) (*Argon2idKDF, error) {
+	const threads = 4
+	salt := make([]byte, SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	const memFloor = 64 * 1024 // 64 MiB, in KiB
+	timeCost := uint32(1)
+	memCost := uint32(memFloor)
+	if uint64(memCost)*1024 > memoryLimit {
+		return nil, fmt.Errorf("crypto: memory limit %d is too small for the minimum Argon2id working set of %d bytes", memoryLimit, uint64(memCost)*1024)
+	}
+	for {
+		start := time.Now()
+		_ = argon2.IDKey(calibrationPassword, salt, timeCost, memCost, threads, KeySize)
+		if time.Since(start) > targetDuration {
+			// This cost overran the budget; fall back to the last cost
+			// that was actually timed within it, unless we're already at
+			// the floor.
+			if timeCost > 1 {
+				timeCost--
+			}
+			if memCost > memFloor {
+				memCost /= 2
+			}
+			break
+		}
+		if uint64(memCost)*2*1024 > memoryLimit {
+			break
+		}
+		timeCost++
+		memCost *= 2
+	}
+	return &Argon2idKDF{Time: timeCost, Memory: memCost, Threads: threads}, nil
+}
@@ -0,0 +1,199 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPBKDF2KDFDerive(t *testing.T) {
+	salt := []byte("testsalt12345678901234567890123")
+	k := &PBKDF2KDF{}
+
+	key, err := k.Derive([]byte("password"), salt, KeySize)
+	if err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if len(key) != KeySize {
+		t.Errorf("Expected key size %d, got %d", KeySize, len(key))
+	}
+
+	want, err := DeriveKey("password", salt)
+	if err != nil {
+		t.Fatalf("DeriveKey() returned error: %v", err)
+	}
+	if !bytes.Equal(key, want) {
+		t.Error("Expected PBKDF2KDF with default iterations to match DeriveKey")
+	}
+}
+
+func TestScryptKDFDerive(t *testing.T) {
+	salt := []byte("testsalt12345678901234567890123")
+	k := &ScryptKDF{N: 1 << 10, R: 8, P: 1}
+
+	key, err := k.Derive([]byte("password"), salt, KeySize)
+	if err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if len(key) != KeySize {
+		t.Errorf("Expected key size %d, got %d", KeySize, len(key))
+	}
+
+	key2, err := k.Derive([]byte("password"), salt, KeySize)
+	if err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if !bytes.Equal(key, key2) {
+		t.Error("Expected same password/salt/params to derive the same key")
+	}
+}
+
+func TestArgon2idKDFDerive(t *testing.T) {
+	salt := []byte("testsalt12345678901234567890123")
+	k := &Argon2idKDF{Time: 1, Memory: 8 * 1024, Threads: 2}
+
+	key, err := k.Derive([]byte("password"), salt, KeySize)
+	if err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if len(key) != KeySize {
+		t.Errorf("Expected key size %d, got %d", KeySize, len(key))
+	}
+}
+
+func TestKDFHeaderRoundTrip(t *testing.T) {
+	cases := []KDF{
+		&PBKDF2KDF{Iterations: 50000},
+		&ScryptKDF{N: 1 << 14, R: 8, P: 1},
+		&Argon2idKDF{Time: 3, Memory: 64 * 1024, Threads: 4},
+	}
+
+	for _, k := range cases {
+		header := MarshalKDFHeader(k)
+		got, n, err := UnmarshalKDFHeader(header)
+		if err != nil {
+			t.Fatalf("UnmarshalKDFHeader() returned error: %v", err)
+		}
+		if n != len(header) {
+			t.Errorf("Expected UnmarshalKDFHeader to consume %d bytes, consumed %d", len(header), n)
+		}
+		if got.ID() != k.ID() {
+			t.Errorf("Expected KDF id %d, got %d", k.ID(), got.ID())
+		}
+		if !bytes.Equal(got.Marshal(), k.Marshal()) {
+			t.Errorf("Expected round-tripped params %x, got %x", k.Marshal(), got.Marshal())
+		}
+	}
+}
+
+func TestKDFHeaderTrailingDataIsPreserved(t *testing.T) {
+	k := &PBKDF2KDF{Iterations: 12345}
+	header := MarshalKDFHeader(k)
+	withTrailer := append(header, []byte("salt-and-ciphertext-follow")...)
+
+	got, n, err := UnmarshalKDFHeader(withTrailer)
+	if err != nil {
+		t.Fatalf("UnmarshalKDFHeader() returned error: %v", err)
+	}
+	if n != len(header) {
+		t.Errorf("Expected to consume exactly the header, consumed %d of %d", n, len(header))
+	}
+	if got.ID() != KDFIDPBKDF2SHA256 {
+		t.Errorf("Expected KDFIDPBKDF2SHA256, got %d", got.ID())
+	}
+}
+
+func TestUnmarshalKDFHeaderRejectsUnknownID(t *testing.T) {
+	if _, _, err := UnmarshalKDFHeader([]byte{0xFF, 0}); err == nil {
+		t.Error("Expected UnmarshalKDFHeader() to reject an unknown KDF id, got nil error")
+	}
+}
+
+func TestUnmarshalKDFHeaderRejectsShortInput(t *testing.T) {
+	if _, _, err := UnmarshalKDFHeader([]byte{0}); err == nil {
+		t.Error("Expected UnmarshalKDFHeader() to reject a truncated header, got nil error")
+	}
+}
+
+func TestCalibrateScrypt(t *testing.T) {
+	const memoryLimit = 64 * 1024 * 1024
+	k, err := CalibrateScrypt(time.Millisecond, memoryLimit)
+	if err != nil {
+		t.Fatalf("CalibrateScrypt() returned error: %v", err)
+	}
+	if k.N < 1<<15 {
+		t.Errorf("Expected N to be at least the starting value %d, got %d", 1<<15, k.N)
+	}
+	if got := uint64(k.N) * uint64(k.R) * 128; got > memoryLimit {
+		t.Errorf("Expected scrypt working set to respect the memory limit, got %d bytes", got)
+	}
+}
+
+func TestCalibrateScryptRespectsTargetDuration(t *testing.T) {
+	const targetDuration = 100 * time.Millisecond
+	// maxAllowed tolerates scheduler/system jitter but is well short of what
+	// one more doubling of N would cost (~2x), so a calibration that returns
+	// an over-budget N still fails this test.
+	const maxAllowed = targetDuration + targetDuration/2
+
+	k, err := CalibrateScrypt(targetDuration, 1024*1024*1024)
+	if err != nil {
+		t.Fatalf("CalibrateScrypt() returned error: %v", err)
+	}
+
+	salt := []byte("testsalt12345678901234567890123")
+	start := time.Now()
+	if _, err := k.Derive([]byte("password"), salt, KeySize); err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > maxAllowed {
+		t.Errorf("Expected returned N=%d to derive within ~%v, took %v", k.N, targetDuration, elapsed)
+	}
+}
+
+func TestCalibrateScryptErrorsWhenFloorExceedsMemoryLimit(t *testing.T) {
+	if _, err := CalibrateScrypt(time.Millisecond, 1<<20); err == nil {
+		t.Error("Expected CalibrateScrypt() to reject a memory limit below the starting N=2^15 floor, got nil error")
+	}
+}
+
+func TestCalibrateArgon2id(t *testing.T) {
+	k, err := CalibrateArgon2id(time.Millisecond, 256*1024*1024)
+	if err != nil {
+		t.Fatalf("CalibrateArgon2id() returned error: %v", err)
+	}
+	if k.Time == 0 {
+		t.Error("Expected a non-zero time cost")
+	}
+	if k.Memory == 0 {
+		t.Error("Expected a non-zero memory cost")
+	}
+}
+
+func TestCalibrateArgon2idRespectsTargetDuration(t *testing.T) {
+	const targetDuration = 100 * time.Millisecond
+	// maxAllowed tolerates scheduler/system jitter but is well short of what
+	// one more cost increase would add (time+1, memory*2), so a calibration
+	// that returns over-budget params still fails this test.
+	const maxAllowed = targetDuration + targetDuration/2
+
+	k, err := CalibrateArgon2id(targetDuration, 1024*1024*1024)
+	if err != nil {
+		t.Fatalf("CalibrateArgon2id() returned error: %v", err)
+	}
+
+	salt := []byte("testsalt12345678901234567890123")
+	start := time.Now()
+	if _, err := k.Derive([]byte("password"), salt, KeySize); err != nil {
+		t.Fatalf("Derive() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > maxAllowed {
+		t.Errorf("Expected returned params (time=%d, memory=%d) to derive within ~%v, took %v", k.Time, k.Memory, targetDuration, elapsed)
+	}
+}
+
+func TestCalibrateArgon2idErrorsWhenFloorExceedsMemoryLimit(t *testing.T) {
+	if _, err := CalibrateArgon2id(time.Millisecond, 1<<20); err == nil {
+		t.Error("Expected CalibrateArgon2id() to reject a memory limit below the starting 64 MiB floor, got nil error")
+	}
+}
@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+const (
+	opensslMagic   = "Salted__"
+	opensslSaltLen = 8
+)
+
+// ErrMissingOpenSSLMagic is returned when data passed to DecryptOpenSSL does
+// not start with the `Salted__` header OpenSSL prefixes to its envelopes.
+var ErrMissingOpenSSLMagic = errors.New("crypto: missing \"Salted__\" magic header")
+
+// CredsGenerator derives an AES-256 key and CBC IV from a passphrase and
+// salt. It abstracts over the different schemes `openssl enc` has used over
+// time, so DecryptOpenSSL can read envelopes produced by older openssl
+// versions as well as current ones.
+type CredsGenerator interface {
+	// Generate returns a 32-byte AES-256 key followed by a 16-byte IV.
+	Generate(passphrase, salt []byte) (key, iv []byte, err error)
+}
+
+// PBKDF2CredsGenerator derives key material with PBKDF2-HMAC-SHA256,
+// matching `openssl enc -aes-256-cbc -pbkdf2`. Iter defaults to Iterations
+// when zero.
+type PBKDF2CredsGenerator struct {
+	Iter int
+}
+
+// Generate implements CredsGenerator.
+func (g PBKDF2CredsGenerator) Generate(passphrase, salt []byte) (key, iv []byte, err error) {
+	iter := g.Iter
+	if iter == 0 {
+		iter = Iterations
+	}
+	derived, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iter, KeySize+aesIVSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return derived[:KeySize], derived[KeySize : KeySize+aesIVSize], nil
+}
+
+// EVPBytesToKeyGenerator derives key material the legacy way, via OpenSSL's
+// EVP_BytesToKey with a configurable digest. This is what `openssl enc`
+// uses when -pbkdf2 is not given, so it reads files written without that
+// flag, including older files.
+type EVPBytesToKeyGenerator struct {
+	New func() hash.Hash
+}
+
+// Generate implements CredsGenerator.
+func (g EVPBytesToKeyGenerator) Generate(passphrase, salt []byte) (key, iv []byte, err error) {
+	if g.New == nil {
+		return nil, nil, errors.New("crypto: EVPBytesToKeyGenerator requires New")
+	}
+	var derived, block []byte
+	for len(derived) < KeySize+aesIVSize {
+		h := g.New()
+		h.Write(block)
+		h.Write(passphrase)
+		h.Write(salt)
+		block = h.Sum(nil)
+		derived = append(derived, block...)
+	}
+	return derived[:KeySize], derived[KeySize : KeySize+aesIVSize], nil
+}
+
+// EVPBytesToKeyMD5 matches `openssl enc` on versions that default to MD5
+// (the historical default before -pbkdf2 and -md existed).
+var EVPBytesToKeyMD5 = EVPBytesToKeyGenerator{New: md5.New}
+
+// EVPBytesToKeySHA1 matches `openssl enc -md sha1`.
+var EVPBytesToKeySHA1 = EVPBytesToKeyGenerator{New: sha1.New}
+
+// EVPBytesToKeySHA256 matches `openssl enc -md sha256`.
+var EVPBytesToKeySHA256 = EVPBytesToKeyGenerator{New: sha256.New}
+
+const aesIVSize = 16
+
+// EncryptOpenSSL encrypts plaintext under passphrase using AES-256-CBC and
+// returns an OpenSSL-compatible `Salted__` envelope: the 8-byte magic, an
+// 8-byte random salt, then the ciphertext. The key and IV are derived with
+// PBKDF2CredsGenerator, matching `openssl enc -aes-256-cbc -pbkdf2`.
+func EncryptOpenSSL(passphrase, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, opensslSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, iv, err := (PBKDF2CredsGenerator{}).Generate(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := encryptCBC(plaintext, key, iv)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(opensslMagic)+opensslSaltLen+len(ciphertext))
+	out = append(out, opensslMagic...)
+	out = append(out, salt...)
+	out = append(out, ciphertext...)
+	return out, nil
+}
+
+// DecryptOpenSSL reverses EncryptOpenSSL. gen selects the key derivation
+// scheme to use; pass nil to derive with PBKDF2CredsGenerator, the scheme
+// current `openssl enc -pbkdf2` uses, or one of the EVPBytesToKey*
+// generators to read files produced by `openssl enc` without -pbkdf2.
+func DecryptOpenSSL(passphrase, ciphertext []byte, gen CredsGenerator) ([]byte, error) {
+	if gen == nil {
+		gen = PBKDF2CredsGenerator{}
+	}
+	if len(ciphertext) < len(opensslMagic)+opensslSaltLen {
+		return nil, ErrCiphertextTooShort
+	}
+	if !bytes.Equal(ciphertext[:len(opensslMagic)], []byte(opensslMagic)) {
+		return nil, fmt.Errorf("%w", ErrMissingOpenSSLMagic)
+	}
+	salt := ciphertext[len(opensslMagic) : len(opensslMagic)+opensslSaltLen]
+	body := ciphertext[len(opensslMagic)+opensslSaltLen:]
+
+	key, iv, err := gen.Generate(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCBC(body, key, iv)
+}
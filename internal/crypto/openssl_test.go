@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func requireOpenSSL(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl not found on PATH, skipping interop test")
+	}
+	return path
+}
+
+func TestEncryptOpenSSLDecryptsWithOpenSSLCLI(t *testing.T) {
+	opensslPath := requireOpenSSL(t)
+
+	passphrase := []byte("correct horse battery staple")
+	plaintext := []byte("this payload was encrypted by the crypto package and must be readable by the openssl CLI")
+
+	envelope, err := EncryptOpenSSL(passphrase, plaintext)
+	if err != nil {
+		t.Fatalf("EncryptOpenSSL() returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	encPath := filepath.Join(dir, "envelope.bin")
+	if err := os.WriteFile(encPath, envelope, 0o600); err != nil {
+		t.Fatalf("failed to write envelope: %v", err)
+	}
+
+	// -iter must match Iterations: EncryptOpenSSL defaults to our own
+	// iteration count, not openssl's (10000 unless -iter is given).
+	out, err := exec.Command(opensslPath, "enc", "-d", "-aes-256-cbc", "-pbkdf2", "-iter", fmt.Sprint(Iterations),
+		"-pass", "pass:"+string(passphrase), "-in", encPath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("openssl failed to decrypt our envelope: %v\n%s", err, out)
+	}
+	if !bytes.Equal(out, plaintext) {
+		t.Errorf("Expected openssl to recover %q, got %q", plaintext, out)
+	}
+}
+
+func TestDecryptOpenSSLReadsOpenSSLCLIOutput(t *testing.T) {
+	opensslPath := requireOpenSSL(t)
+
+	passphrase := []byte("another passphrase")
+	plaintext := []byte("round-tripping data produced by the real openssl binary")
+
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "envelope.bin")
+
+	cmd := exec.Command(opensslPath, "enc", "-aes-256-cbc", "-pbkdf2", "-iter", fmt.Sprint(Iterations),
+		"-pass", "pass:"+string(passphrase), "-out", outPath)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("openssl failed to encrypt: %v\n%s", err, out)
+	}
+
+	envelope, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read openssl output: %v", err)
+	}
+
+	decrypted, err := DecryptOpenSSL(passphrase, envelope, nil)
+	if err != nil {
+		t.Fatalf("DecryptOpenSSL() returned error: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected decrypted %q, got %q", plaintext, decrypted)
+	}
+}
+
+func TestEncryptDecryptOpenSSLRoundTrip(t *testing.T) {
+	passphrase := []byte("round-trip passphrase")
+	plaintext := bytes.Repeat([]byte("envelope payload "), 200)
+
+	envelope, err := EncryptOpenSSL(passphrase, plaintext)
+	if err != nil {
+		t.Fatalf("EncryptOpenSSL() returned error: %v", err)
+	}
+	decrypted, err := DecryptOpenSSL(passphrase, envelope, nil)
+	if err != nil {
+		t.Fatalf("DecryptOpenSSL() returned error: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Error("Expected decrypted payload to match original plaintext")
+	}
+}
+
+func TestDecryptOpenSSLRejectsMissingMagic(t *testing.T) {
+	if _, err := DecryptOpenSSL([]byte("pw"), []byte("not an openssl envelope!!"), nil); err == nil {
+		t.Error("Expected DecryptOpenSSL() to reject data without the Salted__ header, got nil error")
+	}
+}
+
+func TestEVPBytesToKeyGeneratorInterop(t *testing.T) {
+	opensslPath := requireOpenSSL(t)
+
+	passphrase := []byte("legacy passphrase")
+	plaintext := []byte("data encrypted with the legacy EVP_BytesToKey derivation")
+
+	dir := t.TempDir()
+	outPath := filepath.Join(dir, "envelope.bin")
+
+	cmd := exec.Command(opensslPath, "enc", "-aes-256-cbc", "-md", "sha256",
+		"-pass", "pass:"+string(passphrase), "-out", outPath)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("openssl failed to encrypt: %v\n%s", err, out)
+	}
+
+	envelope, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read openssl output: %v", err)
+	}
+
+	decrypted, err := DecryptOpenSSL(passphrase, envelope, EVPBytesToKeySHA256)
+	if err != nil {
+		t.Fatalf("DecryptOpenSSL() returned error: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected decrypted %q, got %q", plaintext, decrypted)
+	}
+}
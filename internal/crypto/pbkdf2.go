@@ -1,8 +1,6 @@
 package crypto
 
 import (
-	"crypto/pbkdf2"
-	"crypto/sha256"
 	"crypto/rand"
 )
 
@@ -18,6 +16,9 @@ func GenerateSalt() ([]byte, error) {
 	return salt, err
 }
 
+// DeriveKey derives a key with PBKDF2KDF, this package's long-standing
+// default KDF. New code that wants to choose or calibrate a different KDF
+// should use the KDF interface directly.
 func DeriveKey(password string, salt []byte) ([]byte, error) {
-	return pbkdf2.Key(sha256.New, password, salt, Iterations, KeySize)
+	return (&PBKDF2KDF{}).Derive([]byte(password), salt, KeySize)
 }
@@ -0,0 +1,204 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// StreamChunkSize is the size of each plaintext frame NewEncryptWriter seals
+// independently, so large steganographic payloads can be encrypted and
+// decrypted without holding the whole file in memory.
+const StreamChunkSize = 64 * 1024
+
+const (
+	streamNoncePrefixSize = 4
+	streamCounterSize     = 8
+	streamFrameHeaderSize = 1 + 4 // final flag + big-endian frame length
+)
+
+// ErrStreamTruncated is returned by a DecryptReader when the underlying
+// reader ends before a frame marked final has been read, meaning the stream
+// was cut short (accidentally or by an attacker).
+var ErrStreamTruncated = errors.New("crypto: stream truncated before final frame")
+
+// NewEncryptWriter wraps w so that every Write call's data is appended to a
+// StreamChunkSize buffer and sealed as an independent AES-GCM frame once
+// full. Each frame's nonce is a random 4-byte prefix (generated once per
+// writer) followed by an 8-byte counter that increments per frame, and the
+// last frame written by Close is authenticated with a "final" flag as
+// associated data so a decrypter can detect truncation. The caller must call
+// Close to flush the final, possibly partial, frame.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	ew := &encryptWriter{w: w, gcm: gcm, buf: make([]byte, 0, StreamChunkSize)}
+	if _, err := rand.Read(ew.prefix[:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(ew.prefix[:]); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	prefix  [streamNoncePrefixSize]byte
+	counter uint64
+	buf     []byte
+	closed  bool
+}
+
+// Write implements io.Writer.
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("crypto: write to closed stream encrypt writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(ew.buf[len(ew.buf):cap(ew.buf)], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+		if len(ew.buf) == cap(ew.buf) {
+			if err := ew.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close flushes the final frame and implements io.Closer. It is safe to call
+// more than once.
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.flush(true)
+}
+
+func (ew *encryptWriter) flush(final bool) error {
+	ad := streamAD(final)
+	sealed := ew.gcm.Seal(nil, ew.nonce(), ew.buf, ad[:])
+	if err := writeStreamFrame(ew.w, ad[0], sealed); err != nil {
+		return err
+	}
+	ew.buf = ew.buf[:0]
+	ew.counter++
+	return nil
+}
+
+func (ew *encryptWriter) nonce() []byte {
+	nonce := make([]byte, ew.gcm.NonceSize())
+	copy(nonce, ew.prefix[:])
+	binary.BigEndian.PutUint64(nonce[streamNoncePrefixSize:], ew.counter)
+	return nonce
+}
+
+func streamAD(final bool) [1]byte {
+	if final {
+		return [1]byte{1}
+	}
+	return [1]byte{0}
+}
+
+func writeStreamFrame(w io.Writer, finalFlag byte, sealed []byte) error {
+	header := make([]byte, streamFrameHeaderSize)
+	header[0] = finalFlag
+	binary.BigEndian.PutUint32(header[1:], uint32(len(sealed)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(sealed)
+	return err
+}
+
+// NewDecryptReader reverses NewEncryptWriter: it reads framed, sealed chunks
+// from r and returns their authenticated plaintext through the returned
+// io.Reader. Reads return ErrStreamTruncated if r ends before the final
+// frame has been read, or an authentication error if a frame (including its
+// final flag) was tampered with.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	dr := &decryptReader{r: r, gcm: gcm}
+	if _, err := io.ReadFull(r, dr.prefix[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStreamTruncated, err)
+	}
+	return dr, nil
+}
+
+type decryptReader struct {
+	r       io.Reader
+	gcm     cipher.AEAD
+	prefix  [streamNoncePrefixSize]byte
+	counter uint64
+	buf     []byte
+	final   bool
+}
+
+// Read implements io.Reader.
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.buf) == 0 {
+		if dr.final {
+			return 0, io.EOF
+		}
+		frame, final, err := dr.readFrame()
+		if err != nil {
+			return 0, err
+		}
+		dr.buf = frame
+		dr.final = final
+	}
+	n := copy(p, dr.buf)
+	dr.buf = dr.buf[n:]
+	return n, nil
+}
+
+func (dr *decryptReader) readFrame() (frame []byte, final bool, err error) {
+	var header [streamFrameHeaderSize]byte
+	if _, err := io.ReadFull(dr.r, header[:]); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrStreamTruncated, err)
+	}
+	finalFlag := header[0]
+	frameLen := binary.BigEndian.Uint32(header[1:])
+	if frameLen > uint32(StreamChunkSize+dr.gcm.Overhead()) {
+		return nil, false, fmt.Errorf("crypto: stream frame length %d exceeds maximum of %d", frameLen, StreamChunkSize+dr.gcm.Overhead())
+	}
+
+	sealed := make([]byte, frameLen)
+	if _, err := io.ReadFull(dr.r, sealed); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrStreamTruncated, err)
+	}
+
+	nonce := make([]byte, dr.gcm.NonceSize())
+	copy(nonce, dr.prefix[:])
+	binary.BigEndian.PutUint64(nonce[streamNoncePrefixSize:], dr.counter)
+
+	plaintext, err := dr.gcm.Open(nil, nonce, sealed, []byte{finalFlag})
+	if err != nil {
+		return nil, false, fmt.Errorf("crypto: stream frame failed authentication: %w", err)
+	}
+	dr.counter++
+	return plaintext, finalFlag == 1, nil
+}
@@ -0,0 +1,223 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreamRoundTripThroughPipe(t *testing.T) {
+	key := testKey(t)
+	plaintext := bytes.Repeat([]byte("streaming steganography payload "), 5000) // spans multiple chunks
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		ew, err := NewEncryptWriter(pw, key)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := ew.Write(plaintext); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(ew.Close())
+	}()
+
+	dr, err := NewDecryptReader(pr, key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() returned error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Error("Expected decrypted stream to match original plaintext")
+	}
+}
+
+func TestStreamRoundTripEmptyPayload(t *testing.T) {
+	key := testKey(t)
+
+	var buf bytes.Buffer
+	ew, err := NewEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter() returned error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	dr, err := NewDecryptReader(&buf, key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() returned error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected empty plaintext, got %d bytes", len(got))
+	}
+}
+
+func TestStreamRoundTripExactChunkMultiple(t *testing.T) {
+	key := testKey(t)
+	plaintext := bytes.Repeat([]byte{0x42}, StreamChunkSize*2)
+
+	var buf bytes.Buffer
+	ew, err := NewEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter() returned error: %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	dr, err := NewDecryptReader(&buf, key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatalf("io.ReadAll() returned error: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Error("Expected decrypted stream to match original plaintext")
+	}
+}
+
+func TestStreamDetectsTruncation(t *testing.T) {
+	key := testKey(t)
+	plaintext := bytes.Repeat([]byte("data that spans more than one chunk "), 5000)
+
+	var buf bytes.Buffer
+	ew, err := NewEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter() returned error: %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	truncated := buf.Bytes()[:buf.Len()-1]
+
+	dr, err := NewDecryptReader(bytes.NewReader(truncated), key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	_, err = io.ReadAll(dr)
+	if !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("Expected ErrStreamTruncated, got %v", err)
+	}
+}
+
+func TestStreamDetectsTamperedFinalFlag(t *testing.T) {
+	key := testKey(t)
+	plaintext := []byte("short payload, single frame")
+
+	var buf bytes.Buffer
+	ew, err := NewEncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("NewEncryptWriter() returned error: %v", err)
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	tampered := buf.Bytes()
+	finalFlagOffset := streamNoncePrefixSize
+	tampered[finalFlagOffset] ^= 1 // flip the final flag on the lone frame
+
+	dr, err := NewDecryptReader(bytes.NewReader(tampered), key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	if _, err := io.ReadAll(dr); err == nil {
+		t.Error("Expected reading a stream with a tampered final flag to fail, got nil error")
+	}
+}
+
+func TestStreamRejectsOversizedFrameLengthWithoutAllocating(t *testing.T) {
+	key := testKey(t)
+
+	header := make([]byte, streamFrameHeaderSize)
+	header[0] = 0
+	binary.BigEndian.PutUint32(header[1:], 0xFFFFFFFF) // a hostile, near-4GiB frame length
+
+	prefix := make([]byte, streamNoncePrefixSize)
+	payload := append(append([]byte{}, prefix...), header...)
+
+	dr, err := NewDecryptReader(bytes.NewReader(payload), key)
+	if err != nil {
+		t.Fatalf("NewDecryptReader() returned error: %v", err)
+	}
+	_, err = io.ReadAll(dr)
+	if err == nil {
+		t.Error("Expected reading a stream with an oversized frame length to fail, got nil error")
+	}
+	// Allocating the claimed frame and reading into it would end in
+	// ErrStreamTruncated; the length check must reject the frame first.
+	if errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("Expected the frame length to be rejected before the frame is read, got %v", err)
+	}
+}
+
+func BenchmarkStreamRoundTrip(b *testing.B) {
+	key := make([]byte, KeySize)
+	plaintext := bytes.Repeat([]byte("benchmark payload "), 50000) // 900,000 bytes, 14 chunks
+
+	b.ResetTimer()
+	for b.Loop() {
+		var buf bytes.Buffer
+		ew, err := NewEncryptWriter(&buf, key)
+		if err != nil {
+			b.Fatalf("NewEncryptWriter() returned error: %v", err)
+		}
+		if _, err := ew.Write(plaintext); err != nil {
+			b.Fatalf("Write() returned error: %v", err)
+		}
+		if err := ew.Close(); err != nil {
+			b.Fatalf("Close() returned error: %v", err)
+		}
+
+		dr, err := NewDecryptReader(&buf, key)
+		if err != nil {
+			b.Fatalf("NewDecryptReader() returned error: %v", err)
+		}
+		if _, err := io.ReadAll(dr); err != nil {
+			b.Fatalf("io.ReadAll() returned error: %v", err)
+		}
+	}
+}
+
+// BenchmarkOneShotAES256GCMLargePayload benchmarks the non-streaming API
+// against the same payload size as BenchmarkStreamRoundTrip, for comparison.
+func BenchmarkOneShotAES256GCMLargePayload(b *testing.B) {
+	key := make([]byte, KeySize)
+	plaintext := bytes.Repeat([]byte("benchmark payload "), 50000)
+
+	b.ResetTimer()
+	for b.Loop() {
+		ciphertext, err := EncryptAES256(plaintext, key, nil, ModeGCM)
+		if err != nil {
+			b.Fatalf("EncryptAES256() returned error: %v", err)
+		}
+		if _, err := DecryptAES256(ciphertext, key, nil, ModeGCM); err != nil {
+			b.Fatalf("DecryptAES256() returned error: %v", err)
+		}
+	}
+}